@@ -8,23 +8,26 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net/http"
-	"regexp"
-	"runtime"
 	"strings"
 
-	"github.com/google/go-cmp/cmp"
-	"golang.org/x/exp/slices"
 	"golang.org/x/vulndb/cmd/vulnreport/log"
-	"golang.org/x/vulndb/internal/osvutils"
+	"golang.org/x/vulndb/internal/genai"
+	"golang.org/x/vulndb/internal/proxy"
+	"golang.org/x/vulndb/internal/refcheck"
 	"golang.org/x/vulndb/internal/report"
+	"golang.org/x/vulndb/internal/report/checks"
 	"golang.org/x/vulndb/internal/symbols"
 )
 
 var (
-	force       = flag.Bool("f", false, "for fix, force Fix to run even if there are no lint errors")
-	skipAlias   = flag.Bool("skip-alias", false, "for fix, skip adding new GHSAs and CVEs")
-	skipSymbols = flag.Bool("skip-symbols", false, "for lint and fix, don't load package for symbols checks")
+	force               = flag.Bool("f", false, "for fix, force Fix to run even if there are no lint errors")
+	skipAlias           = flag.Bool("skip-alias", false, "for fix, skip adding new GHSAs and CVEs")
+	skipSymbols         = flag.Bool("skip-symbols", false, "for lint and fix, don't load package for symbols checks")
+	aiAssist            = flag.Bool("ai", false, "for fix, use the genai advisory server to draft TODO fields (descriptions, summaries, CWEs)")
+	refCheckConcurrency = flag.Int("ref-concurrency", 10, "for fix, number of reference URLs to check concurrently")
+	fixRefs             = flag.Bool("fix-refs", false, "for fix, rewrite redirected reference URLs in place and drop duplicates")
+	symbolsMode         = flag.String("mode", "source", "for lint and fix, which symbol-derivation mode(s) to run: source, binary, or both")
+	offline             = flag.Bool("offline", false, "for fix, don't hit the network: use in-memory stubs for reference checks, symbol checks, and alias lookups")
 )
 
 type fix struct {
@@ -45,24 +48,82 @@ func (f *fix) setup(ctx context.Context) error {
 	return setupAll(ctx, f.fixer)
 }
 
-func (*fix) close() error { return nil }
+func (f *fix) close() error { return f.fixer.close() }
 
 func (f *fix) run(ctx context.Context, input any) error {
 	r := input.(*yamlReport)
 	return f.fixAndWriteAll(ctx, r)
 }
 
+// fixer depends on a small set of interfaces (checks.RefChecker,
+// checks.SymbolChecker, checks.AliasSource) for everything that touches
+// the network, rather than calling http.Head, symbols.Exported, or an
+// alias source directly. That makes it possible to run `vulnreport fix`
+// offline (see -offline) or against the in-memory fakes in
+// internal/report/checks under test.
 type fixer struct {
 	*linter
 	*aliasFinder
+	ai *genai.AdvisoryServer
+
+	refs    checks.RefChecker
+	syms    checks.SymbolChecker
+	aliases checks.AliasSource
 }
 
+// refCacheFile is where the reference-check cache persists between
+// vulnreport fix runs.
+const refCacheFile = ".refcheck-cache.json"
+
 func (f *fixer) setup(ctx context.Context) error {
 	f.linter = new(linter)
 	f.aliasFinder = new(aliasFinder)
+	if *aiAssist {
+		client, err := genai.NewGeminiClient(ctx)
+		if err != nil {
+			return fmt.Errorf("-ai: %w", err)
+		}
+		f.ai = genai.NewAdvisoryServer(client)
+	}
+
+	if *offline {
+		f.refs = checks.FakeRefChecker{}
+		f.syms = checks.FakeSymbolChecker{}
+		f.aliases = checks.FakeAliasSource{}
+	} else {
+		rc := refcheck.New(*refCheckConcurrency)
+		if err := rc.LoadCache(refCacheFile); err != nil {
+			log.Warnf("could not load reference-check cache: %s", err)
+		}
+		f.refs = rc
+		f.syms = liveSymbolChecker{}
+		f.aliases = f.aliasFinder
+	}
+
 	return setupAll(ctx, f.linter, f.aliasFinder)
 }
 
+func (f *fixer) close() error {
+	if rc, ok := f.refs.(*refcheck.Checker); ok {
+		if err := rc.SaveCache(refCacheFile); err != nil {
+			log.Warnf("could not save reference-check cache: %s", err)
+		}
+	}
+	return nil
+}
+
+// liveSymbolChecker is the real checks.SymbolChecker, backed by
+// internal/symbols.
+type liveSymbolChecker struct{}
+
+func (liveSymbolChecker) Exported(m *report.Module, p *report.Package) ([]string, error) {
+	return symbols.Exported(m, p)
+}
+
+func (liveSymbolChecker) Binary(pc *proxy.Client, m *report.Module, p *report.Package, candidates []string) ([]string, error) {
+	return symbols.Binary(pc, m, p, candidates)
+}
+
 func (f *fixer) fixAndWriteAll(ctx context.Context, r *yamlReport) error {
 	fixed := f.fix(ctx, r, false)
 
@@ -88,28 +149,44 @@ func (f *fixer) fix(ctx context.Context, r *yamlReport, addNotes bool) (fixed bo
 		}
 		fixed = false
 	}
+	warn := func(f string, v ...any) { log.Warnf(r.ID+": "+f, v...) }
 
 	if lints := r.Lint(f.pc); *force || len(lints) > 0 {
 		r.Fix(f.pc)
 	}
 
 	if !*skipSymbols {
-		log.Infof("%s: checking packages and symbols (use -skip-symbols to skip this)", r.ID)
-		if err := r.checkSymbols(); err != nil {
+		log.Infof("%s: checking packages and symbols (use -skip-symbols to skip this, -mode to choose source/binary/both)", r.ID)
+		mode := checks.Mode(*symbolsMode)
+		if err := checks.Symbols(ctx, mode, f.pc, f.syms, r.Report, warn); err != nil {
 			fixErr("package or symbol error: %s", err)
 		}
 	}
 
 	if !*skipAlias {
 		log.Infof("%s: checking for missing GHSAs and CVEs (use -skip-alias to skip this)", r.ID)
-		if added := r.addMissingAliases(ctx, f.aliasFinder); added > 0 {
+		if added := r.addMissingAliases(ctx, f.aliases); added > 0 {
 			log.Infof("%s: added %d missing aliases", r.ID, added)
 		}
+
+		if related, err := checks.RelatedVulns(ctx, f.aliases, r.Report); err != nil {
+			fixErr("finding related cross-ecosystem vulns: %s", err)
+		} else if len(related) > 0 {
+			log.Infof("%s: found related cross-ecosystem vulns: %s", r.ID, strings.Join(related, ", "))
+			r.RelatedVulns = related
+		}
+	}
+
+	if f.ai != nil {
+		log.Infof("%s: drafting TODO fields with the genai advisory server (-ai)", r.ID)
+		if err := f.draftTODOs(ctx, r); err != nil {
+			fixErr("genai draft: %s", err)
+		}
 	}
 
 	// For now, this is a fix check instead of a lint.
 	log.Infof("%s: checking that all references are reachable", r.ID)
-	checkRefs(r.References, fixErr)
+	r.References = checks.Refs(ctx, f.refs, r.References, *fixRefs, fixErr, warn)
 
 	// Check for remaining lint errors.
 	if addNotes {
@@ -127,111 +204,68 @@ func (f *fixer) fix(ctx context.Context, r *yamlReport, addNotes bool) (fixed bo
 	return fixed
 }
 
-func checkRefs(refs []*report.Reference, fixErr func(f string, v ...any)) {
-	for _, r := range refs {
-		resp, err := http.Head(r.URL)
-		if err != nil {
-			fixErr("%q may not exist: %v", r.URL, err)
-			continue
-		}
-		defer resp.Body.Close()
+// draftTODOs fills in any of r.Description, r.Summary, and
+// r.CVEMetadata.CWE that are still set to the "TODO" placeholder emitted
+// by cveToReport/cve5ToReport, using f.ai. The source text for all three
+// is the fetched CVE/GHSA advisory text behind r's existing aliases
+// (see checks.AliasSource.FetchAdvisoryText), not the placeholder itself.
+// draftTODOs takes the server's first (preferred) candidate for each
+// field; the rest are discarded, since there is currently no way for a
+// human to pick among them from the CLI.
+func (f *fixer) draftTODOs(ctx context.Context, r *yamlReport) error {
+	id := r.CVEMetadata
+
+	rawText, err := f.aliases.FetchAdvisoryText(ctx, r.Report)
+	if err != nil {
+		return fmt.Errorf("fetching advisory text: %w", err)
+	}
+	if rawText == "" {
+		return fmt.Errorf("no advisory text available for %s's aliases", r.ID)
+	}
 
-		// For now, only error on status 404, which is unambiguously a problem.
-		// An experiment to error on all non-200 status codes brought up some
-		// ambiguous cases where the link is still viewable in a browser, e.g.:
-		// - 429 Too Many Requests (https://vuldb.com/)
-		// - 503 Service Unavailable (http://blog.recurity-labs.com/2017-08-10/scm-vulns):
-		// - 403 Forbidden (https://www.sap.com/documents/2022/02/fa865ea4-167e-0010-bca6-c68f7e60039b.html)
-		if resp.StatusCode == http.StatusNotFound {
-			fixErr("%q may not exist: HTTP GET returned status %s", r.URL, resp.Status)
-		}
+	// The advisory server caches by CVE/GHSA ID, so key the description
+	// and summary requests by r's first alias rather than its Go ID.
+	aliasID := r.ID
+	if aliases := r.GetAliases(); len(aliases) > 0 {
+		aliasID = aliases[0]
 	}
-}
 
-func (r *yamlReport) checkSymbols() error {
-	if r.IsExcluded() {
-		log.Infof("%s: excluded, skipping symbol checks", r.ID)
-		return nil
-	}
-	for _, m := range r.Modules {
-		if m.IsFirstParty() {
-			gover := runtime.Version()
-			ver := semverForGoVersion(gover)
-			// If some symbol is in the std library at a different version,
-			// we may derive the wrong symbols for this package and other.
-			// In this case, skip updating DerivedSymbols.
-			affected, err := osvutils.AffectsSemver(report.AffectedRanges(m.Versions), ver)
-			if err != nil {
-				return err
-			}
-			if ver == "" || !affected {
-				log.Warnf("%s: current Go version %q is not in a vulnerable range, skipping symbol checks for module %s", r.ID, gover, m.Module)
-				continue
-			}
-			if ver != m.VulnerableAt {
-				log.Warnf("%s: current Go version %q does not match vulnerable_at version (%s) for module %s", r.ID, ver, m.VulnerableAt, m.Module)
+	if r.Description == "TODO" || r.Description == "" {
+		var resp genai.DraftDescriptionResponse
+		req := &genai.DraftDescriptionRequest{ID: aliasID, RawText: rawText}
+		if len(r.Modules) > 0 {
+			req.Module = r.Modules[0].Module
+			if len(r.Modules[0].Packages) > 0 {
+				req.Package = r.Modules[0].Packages[0].Package
 			}
 		}
-
-		for _, p := range m.Packages {
-			if p.SkipFix != "" {
-				log.Infof("%s: skipping symbol checks for package %s (reason: %q)", r.ID, p.Package, p.SkipFix)
-				continue
-			}
-			syms, err := symbols.Exported(m, p)
-			if err != nil {
-				return fmt.Errorf("package %s: %w", p.Package, err)
-			}
-			// Remove any derived symbols that were marked as excluded by a human.
-			syms = removeExcluded(r.ID, syms, p.ExcludedSymbols)
-			if !cmp.Equal(syms, p.DerivedSymbols) {
-				p.DerivedSymbols = syms
-				log.Infof("%s: updated derived symbols for package %s", r.ID, p.Package)
-			}
+		if err := f.ai.DraftDescription(req, &resp); err != nil {
+			return err
+		}
+		if len(resp.Candidates) > 0 {
+			r.Description = report.Description(resp.Candidates[0])
 		}
 	}
 
-	return nil
-}
-
-func removeExcluded(id string, syms, excluded []string) []string {
-	if len(excluded) == 0 {
-		return syms
-	}
-	var newSyms []string
-	for _, d := range syms {
-		if slices.Contains(excluded, d) {
-			log.Infof("%s: removed excluded symbol %s", id, d)
-			continue
+	if r.Summary == "TODO" || r.Summary == "" {
+		var resp genai.SummarizeCVEResponse
+		if err := f.ai.SummarizeCVE(&genai.SummarizeCVERequest{ID: aliasID, Description: rawText}, &resp); err != nil {
+			return err
+		}
+		if len(resp.Candidates) > 0 {
+			r.Summary = report.Summary(resp.Candidates[0])
 		}
-		newSyms = append(newSyms, d)
 	}
-	return newSyms
-}
 
-// Regexp for matching go tags. The groups are:
-// 1  the major.minor version
-// 2  the patch version, or empty if none
-// 3  the entire prerelease, if present
-// 4  the prerelease type ("beta" or "rc")
-// 5  the prerelease number
-var tagRegexp = regexp.MustCompile(`^go(\d+\.\d+)(\.\d+|)((beta|rc)(\d+))?$`)
-
-// versionForTag returns the semantic version for a Go version string,
-// or "" if the version string doesn't correspond to a Go release or beta.
-func semverForGoVersion(v string) string {
-	m := tagRegexp.FindStringSubmatch(v)
-	if m == nil {
-		return ""
-	}
-	version := m[1]
-	if m[2] != "" {
-		version += m[2]
-	} else {
-		version += ".0"
-	}
-	if m[3] != "" {
-		version += "-" + m[4] + "." + m[5]
+	if id != nil && id.CWE == "TODO" {
+		var resp genai.SuggestCWEResponse
+		if err := f.ai.SuggestCWE(&genai.SuggestCWERequest{ID: id.ID, Description: rawText}, &resp); err != nil {
+			return err
+		}
+		if len(resp.Candidates) > 0 {
+			id.CWE = resp.Candidates[0]
+		}
 	}
-	return version
+
+	return nil
 }
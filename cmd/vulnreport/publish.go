@@ -0,0 +1,102 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/vulndb/cmd/vulnreport/log"
+	"golang.org/x/vulndb/internal/publish"
+	"golang.org/x/vulndb/internal/report"
+)
+
+var (
+	publishOut = flag.String("out", "db", "for publish, directory to write the generated database to")
+	diffFrom   = flag.String("diff-from", "", "for publish, path to a previously-published database to diff against, rewriting only modules whose vulns changed")
+)
+
+// publishCmd generates the on-disk, v1-compatible vulnerability database
+// layout (see internal/publish) from the given YAML reports, so that it
+// can be served as a static file tree supporting filtered, by-module
+// downloads.
+type publishCmd struct {
+	filenameParser
+	noSkip
+
+	entries []publish.Entry
+}
+
+func (publishCmd) name() string { return "publish" }
+
+func (publishCmd) usage() (string, string) {
+	const desc = "generate the OSV v1 index/by-ID database layout from one or more YAML reports"
+	return filenameArgs, desc
+}
+
+func (*publishCmd) setup(ctx context.Context) error { return nil }
+
+func (p *publishCmd) run(ctx context.Context, input any) error {
+	r := input.(*yamlReport)
+	modified := time.Now()
+	if r.Withdrawn != nil {
+		modified = *r.Withdrawn
+	} else if t, err := gitLastModified(report.GetReportFilename(r.ID)); err != nil {
+		// Fall back to time.Now, but warn: every module will look
+		// changed on the next -diff-from run until this report gets a
+		// real commit.
+		log.Warnf("%s: could not determine last-modified time from git history, using time.Now: %s", r.ID, err)
+	} else {
+		modified = t
+	}
+	osvEntry := r.GenerateOSVEntry(r.ID, modified)
+	p.entries = append(p.entries, publish.Entry{
+		ID:       r.ID,
+		Modified: osvEntry.Modified.Time,
+		OSV:      osvEntry,
+	})
+	return nil
+}
+
+// close writes out the database once all reports have been processed.
+func (p *publishCmd) close() error {
+	if *diffFrom == "" {
+		log.Infof("publish: writing full snapshot to %s", *publishOut)
+		return publish.Generate(*publishOut, p.entries)
+	}
+
+	prev, err := publish.ReadModules(*diffFrom)
+	if err != nil {
+		return err
+	}
+	changed, err := publish.GenerateDiff(*publishOut, p.entries, prev)
+	if err != nil {
+		return err
+	}
+	log.Infof("publish: rewrote %d modules (diff from %s)", len(changed), *diffFrom)
+	return nil
+}
+
+// gitLastModified returns the commit time of the most recent commit that
+// touched path, so that an unchanged report gets the same Modified time
+// on every vulnreport publish run. Without that stability,
+// GenerateDiff's sameModuleVulns comparison (which checks Modified.Equal)
+// would see every module as changed on every run, making -diff-from a
+// no-op.
+func gitLastModified(path string) (time.Time, error) {
+	out, err := exec.Command("git", "log", "-1", "--format=%cI", "--", path).Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("git log %s: %w", path, err)
+	}
+	s := strings.TrimSpace(string(out))
+	if s == "" {
+		return time.Time{}, fmt.Errorf("%s has no commit history", path)
+	}
+	return time.Parse(time.RFC3339, s)
+}
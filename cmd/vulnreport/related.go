@@ -0,0 +1,185 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/vulndb/cmd/vulnreport/log"
+	"golang.org/x/vulndb/internal/report"
+)
+
+// osvDevVulnAPI is the OSV.dev endpoint for looking up a single vuln by
+// ID, per https://osv.dev/docs/#tag/api.
+const osvDevVulnAPI = "https://api.osv.dev/v1/vulns/"
+
+// osvDevEntry is the subset of the OSV.dev vuln schema needed to follow
+// its alias graph.
+type osvDevEntry struct {
+	ID       string   `json:"id"`
+	Summary  string   `json:"summary,omitempty"`
+	Details  string   `json:"details,omitempty"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Related  []string `json:"related,omitempty"`
+	Affected []struct {
+		Package struct {
+			Name string `json:"name"`
+		} `json:"package"`
+	} `json:"affected,omitempty"`
+}
+
+// queueItem is a pending OSV.dev lookup in FindRelatedVulns's traversal.
+// expand is false for entries reached via "related": OSV.dev's related
+// field only means "possibly the same vuln" (unlike aliases, which means
+// "is the same vuln"), so those entries are checked but not themselves
+// expanded, keeping the traversal to aliases plus one hop of related.
+type queueItem struct {
+	id     string
+	expand bool
+}
+
+// FindRelatedVulns performs alias discovery for r: starting from r's
+// existing aliases (CVEs, GHSAs), it queries OSV.dev for each ID and
+// follows the aliases field transitively to a fixpoint, plus one hop of
+// the related field, collecting IDs from every namespace (CVE, GHSA,
+// PYSEC, RUSTSEC, etc.) along the way.
+//
+// It returns the non-Go IDs among those discovered, other than r's own
+// existing aliases, whose affected
+// package names match one of r's modules or packages -- i.e. vulns in
+// other ecosystems that are plausibly the same underlying issue.
+//
+// FindRelatedVulns makes aliasFinder satisfy checks.AliasSource.
+func (af *aliasFinder) FindRelatedVulns(ctx context.Context, r *report.Report) ([]string, error) {
+	pkgNames := make(map[string]bool)
+	for _, m := range r.Modules {
+		pkgNames[m.Module] = true
+		for _, p := range m.Packages {
+			pkgNames[p.Package] = true
+		}
+	}
+
+	seeds := r.GetAliases()
+	isSeed := make(map[string]bool, len(seeds))
+	seen := make(map[string]bool, len(seeds))
+	queue := make([]queueItem, 0, len(seeds))
+	for _, id := range seeds {
+		isSeed[id] = true
+		if !seen[id] {
+			seen[id] = true
+			queue = append(queue, queueItem{id: id, expand: true})
+		}
+	}
+
+	related := make(map[string]bool)
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+
+		entry, err := af.fetchOSVDevEntry(ctx, item.id)
+		if err != nil {
+			log.Warnf("%s: findRelatedVulns(%s): %s", r.ID, item.id, err)
+			continue
+		}
+
+		// r's own aliases are already in GetAliases; RelatedVulns never
+		// repeats them.
+		if !isGoID(item.id) && !isSeed[item.id] && affectsOneOf(entry, pkgNames) {
+			related[item.id] = true
+		}
+
+		if !item.expand {
+			continue
+		}
+		for _, next := range entry.Aliases {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, queueItem{id: next, expand: true})
+			}
+		}
+		for _, next := range entry.Related {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, queueItem{id: next, expand: false})
+			}
+		}
+	}
+
+	var out []string
+	for id := range related {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// FetchAdvisoryText returns the raw OSV.dev advisory text (details,
+// falling back to summary) for the first of r's existing aliases that
+// OSV.dev has an entry for. It is the source text fixer.draftTODOs feeds
+// to the genai advisory server, rather than the literal "TODO"
+// placeholder.
+//
+// FetchAdvisoryText makes aliasFinder satisfy checks.AliasSource.
+func (af *aliasFinder) FetchAdvisoryText(ctx context.Context, r *report.Report) (string, error) {
+	var lastErr error
+	for _, id := range r.GetAliases() {
+		entry, err := af.fetchOSVDevEntry(ctx, id)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if entry.Details != "" {
+			return entry.Details, nil
+		}
+		if entry.Summary != "" {
+			return entry.Summary, nil
+		}
+	}
+	return "", lastErr
+}
+
+// affectsOneOf reports whether entry's affected packages include one of
+// pkgNames.
+func affectsOneOf(entry osvDevEntry, pkgNames map[string]bool) bool {
+	for _, a := range entry.Affected {
+		if pkgNames[a.Package.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// isGoID reports whether id is a Go advisory ID (GO-YYYY-NNNN), which
+// should never appear in RelatedVulns -- that field is for cross-
+// ecosystem linkage, not self-references.
+func isGoID(id string) bool {
+	return strings.HasPrefix(id, "GO-")
+}
+
+// fetchOSVDevEntry fetches a single entry from OSV.dev by ID.
+func (af *aliasFinder) fetchOSVDevEntry(ctx context.Context, id string) (osvDevEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, osvDevVulnAPI+id, nil)
+	if err != nil {
+		return osvDevEntry{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return osvDevEntry{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return osvDevEntry{}, fmt.Errorf("osv.dev: %s: status %s", id, resp.Status)
+	}
+	var entry osvDevEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		return osvDevEntry{}, fmt.Errorf("osv.dev: %s: %w", id, err)
+	}
+	return entry, nil
+}
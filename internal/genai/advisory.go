@@ -0,0 +1,201 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genai
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+)
+
+// Backend generates text candidates for a prompt. GeminiClient satisfies
+// this interface; tests can supply a fake.
+type Backend interface {
+	GenerateText(ctx context.Context, prompt string) ([]string, error)
+}
+
+// AdvisoryServer is a long-running server that drafts advisory text for a
+// partial report, backed by a Backend. It can be served over JSON-RPC
+// (via Serve) so that it runs as a separate process from vulnreport, or
+// called directly in-process (as fixer does) when that overhead isn't
+// needed.
+//
+// Responses are cached by CVE/GHSA ID and method, since the same ID is
+// often looked up more than once in a single `vulnreport fix` run (e.g.
+// once per lint pass) and model calls are not free.
+type AdvisoryServer struct {
+	backend Backend
+
+	mu    sync.Mutex
+	cache map[string][]string // keyed by method + ":" + id
+}
+
+// NewAdvisoryServer creates a server that drafts advisory text using
+// backend.
+func NewAdvisoryServer(backend Backend) *AdvisoryServer {
+	return &AdvisoryServer{
+		backend: backend,
+		cache:   make(map[string][]string),
+	}
+}
+
+// Serve registers s and serves JSON-RPC requests from lis until it is
+// closed or ctx is done.
+func (s *AdvisoryServer) Serve(ctx context.Context, lis net.Listener) error {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("AdvisoryServer", s); err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		lis.Close()
+	}()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// DraftDescriptionRequest is the request for the draftDescription method.
+type DraftDescriptionRequest struct {
+	// ID is the CVE or GHSA ID the description is being drafted for,
+	// used as the cache key alongside the method name.
+	ID string
+	// Module and Package identify the vulnerable code, as in report.Package.
+	Module, Package string
+	// RawText is the CVE/GHSA description to rewrite into the Go
+	// advisory style (see the style guide referenced in report.Report).
+	RawText string
+}
+
+// DraftDescriptionResponse holds the candidate descriptions, ranked with
+// the server's preferred candidate first.
+type DraftDescriptionResponse struct {
+	Candidates []string
+}
+
+// DraftDescription drafts a Description for r.Report, given the raw
+// CVE/GHSA text fetched for it.
+func (s *AdvisoryServer) DraftDescription(req *DraftDescriptionRequest, resp *DraftDescriptionResponse) error {
+	key := "draftDescription:" + req.ID
+	candidates, err := s.generate(key, fmt.Sprintf(
+		"Rewrite the following vulnerability description for the package %q (module %q) "+
+			"in the style of a Go vulnerability advisory: concise, third person, starting "+
+			"with the name of the affected symbol or feature.\n\n%s", req.Package, req.Module, req.RawText))
+	if err != nil {
+		return err
+	}
+	resp.Candidates = candidates
+	return nil
+}
+
+// SuggestCWERequest is the request for the suggestCWE method.
+type SuggestCWERequest struct {
+	ID          string // the CVE or GHSA ID
+	Description string
+}
+
+// SuggestCWEResponse holds the candidate CWE IDs, ranked with the
+// server's preferred candidate first.
+type SuggestCWEResponse struct {
+	Candidates []string
+}
+
+// SuggestCWE suggests a CWE (Common Weakness Enumeration) ID for
+// CVEMeta.CWE, given the vuln's description.
+func (s *AdvisoryServer) SuggestCWE(req *SuggestCWERequest, resp *SuggestCWEResponse) error {
+	candidates, err := s.generate("suggestCWE:"+req.ID, fmt.Sprintf(
+		"Suggest the single most applicable CWE ID (e.g. \"CWE-79\") for a vulnerability "+
+			"with this description, and nothing else:\n\n%s", req.Description))
+	if err != nil {
+		return err
+	}
+	resp.Candidates = candidates
+	return nil
+}
+
+// SummarizeCVERequest is the request for the summarizeCVE method.
+type SummarizeCVERequest struct {
+	ID          string
+	Description string
+}
+
+// SummarizeCVEResponse holds the candidate one-line summaries, ranked
+// with the server's preferred candidate first.
+type SummarizeCVEResponse struct {
+	Candidates []string
+}
+
+// SummarizeCVE drafts a short Summary for report.Report, given the full
+// CVE/GHSA description.
+func (s *AdvisoryServer) SummarizeCVE(req *SummarizeCVERequest, resp *SummarizeCVEResponse) error {
+	candidates, err := s.generate("summarizeCVE:"+req.ID, fmt.Sprintf(
+		"Summarize the following vulnerability description in a single sentence of no "+
+			"more than 100 characters, suitable as a one-line advisory title:\n\n%s", req.Description))
+	if err != nil {
+		return err
+	}
+	resp.Candidates = candidates
+	return nil
+}
+
+// ProposeAliasesRequest is the request for the proposeAliases method.
+type ProposeAliasesRequest struct {
+	ID          string
+	Description string
+}
+
+// ProposeAliasesResponse holds candidate alias IDs (other CVE/GHSA IDs
+// that the model believes refer to the same vuln) mentioned in or
+// inferable from the description.
+type ProposeAliasesResponse struct {
+	Candidates []string
+}
+
+// ProposeAliases looks for other CVE/GHSA IDs that the description
+// itself suggests are aliases, as a cheap first pass before the real
+// alias-finding logic (aliasFinder) queries OSV.dev and friends.
+func (s *AdvisoryServer) ProposeAliases(req *ProposeAliasesRequest, resp *ProposeAliasesResponse) error {
+	candidates, err := s.generate("proposeAliases:"+req.ID, fmt.Sprintf(
+		"List any CVE or GHSA identifiers, other than %s, mentioned in the following "+
+			"text. Respond with one ID per line and nothing else:\n\n%s", req.ID, req.Description))
+	if err != nil {
+		return err
+	}
+	resp.Candidates = candidates
+	return nil
+}
+
+// generate returns the cached candidates for key, or calls the backend
+// and caches the result if there is no cache entry yet.
+func (s *AdvisoryServer) generate(key, prompt string) ([]string, error) {
+	s.mu.Lock()
+	if cached, ok := s.cache[key]; ok {
+		s.mu.Unlock()
+		return cached, nil
+	}
+	s.mu.Unlock()
+
+	candidates, err := s.backend.GenerateText(context.Background(), prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[key] = candidates
+	s.mu.Unlock()
+	return candidates, nil
+}
@@ -28,7 +28,6 @@ type closer interface {
 
 const (
 	geminiAPIKeyEnv = "GEMINI_API_KEY"
-	geminiAPIKeyEnv = "GEMINI_API_KEYs"
 	geminiModel     = "gemini-pro"
 )
 
@@ -0,0 +1,272 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package publish generates an on-disk vulnerability database in the OSV
+// v1 "index/by-ID" layout used by pkgsite and gopls clients, so that they
+// can download a filtered subset of the database (e.g. by module prefix)
+// instead of the whole thing.
+//
+// The generated layout looks like:
+//
+//	index/db.json       - top-level metadata (last-modified time)
+//	index/modules.json  - module path -> summarized vulns affecting it
+//	ID/GO-YYYY-NNNN.json - the full OSV entry for each vuln
+package publish
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"golang.org/x/vulndb/internal/derrors"
+	"golang.org/x/vulndb/internal/osv"
+)
+
+const (
+	// IndexDir is the directory, relative to the database root, that
+	// holds the index files consumed by ByPackagePrefix-style clients.
+	IndexDir = "index"
+
+	// DBFilename is the name of the top-level database metadata file.
+	DBFilename = "db.json"
+
+	// ModulesFilename is the name of the file that maps module paths to
+	// the vulns affecting them.
+	ModulesFilename = "modules.json"
+)
+
+// DBMeta is the contents of index/db.json.
+type DBMeta struct {
+	// Modified is the time of the most recent change to the database.
+	Modified time.Time `json:"modified"`
+}
+
+// ModuleVuln summarizes how a single vuln affects a module, without
+// requiring a client to fetch the full OSV entry to find out.
+type ModuleVuln struct {
+	ID string `json:"id"`
+	// Modified is the last-modified time of the OSV entry, so a client
+	// can tell whether it needs to re-fetch ID/<ID>.json.
+	Modified time.Time `json:"modified"`
+	// Fixed and Introduced are the sorted, de-duplicated set of versions
+	// at which the vuln was fixed or introduced in this module, across
+	// all of the module's affected ranges.
+	Fixed      []string `json:"fixed,omitempty"`
+	Introduced []string `json:"introduced,omitempty"`
+}
+
+// Modules maps a module path to the vulns that affect it. It is the
+// decoded form of index/modules.json.
+type Modules map[string][]ModuleVuln
+
+// Entry is a single vuln to be published, paired with its OSV ID and
+// last-modified time.
+type Entry struct {
+	ID       string
+	Modified time.Time
+	OSV      osv.Entry
+}
+
+// Generate writes a full snapshot of the database rooted at dbPath,
+// overwriting any existing contents. It is equivalent to calling
+// GenerateDiff with an empty previous Modules map.
+func Generate(dbPath string, entries []Entry) (err error) {
+	derrors.Wrap(&err, "Generate(%s)", dbPath)
+	_, err = GenerateDiff(dbPath, entries, nil)
+	return err
+}
+
+// GenerateDiff writes dbPath, only rewriting the per-ID files and the
+// index/modules.json entries for modules whose vulns changed relative to
+// prev (the previously-published Modules index, typically read back via
+// ReadModules). Passing a nil prev forces a full rewrite.
+//
+// The update is atomic: the new index/db.json and index/modules.json are
+// written to temporary files and renamed into place only after every
+// ID/*.json file has been written successfully.
+//
+// GenerateDiff returns the set of module paths that were rewritten.
+func GenerateDiff(dbPath string, entries []Entry, prev Modules) (changed []string, err error) {
+	derrors.Wrap(&err, "GenerateDiff(%s, %d entries)", dbPath, len(entries))
+
+	modules := toModules(entries)
+	if err := validate(entries, modules); err != nil {
+		return nil, err
+	}
+
+	idDir := filepath.Join(dbPath, "ID")
+	if err := os.MkdirAll(idDir, 0755); err != nil {
+		return nil, err
+	}
+	indexDir := filepath.Join(dbPath, IndexDir)
+	if err := os.MkdirAll(indexDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var lastModified time.Time
+	for _, e := range entries {
+		if e.Modified.After(lastModified) {
+			lastModified = e.Modified
+		}
+		if !moduleSetChanged(modules, prev, e) {
+			continue
+		}
+		if err := writeJSONAtomic(filepath.Join(idDir, e.ID+".json"), e.OSV); err != nil {
+			return nil, err
+		}
+	}
+
+	for mod, vulns := range modules {
+		if prev == nil || !sameModuleVulns(vulns, prev[mod]) {
+			changed = append(changed, mod)
+		}
+	}
+	sort.Strings(changed)
+
+	if err := writeJSONAtomic(filepath.Join(indexDir, ModulesFilename), modules); err != nil {
+		return nil, err
+	}
+	if err := writeJSONAtomic(filepath.Join(indexDir, DBFilename), DBMeta{Modified: lastModified}); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// ReadModules reads back a previously-published index/modules.json, for
+// use as the prev argument to GenerateDiff.
+func ReadModules(dbPath string) (Modules, error) {
+	b, err := os.ReadFile(filepath.Join(dbPath, IndexDir, ModulesFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Modules
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// toModules builds the index/modules.json contents from entries.
+func toModules(entries []Entry) Modules {
+	modules := make(Modules)
+	for _, e := range entries {
+		for _, affected := range e.OSV.Affected {
+			mv := ModuleVuln{ID: e.ID, Modified: e.Modified}
+			for _, r := range affected.Ranges {
+				for _, ev := range r.Events {
+					if ev.Introduced != "" {
+						mv.Introduced = appendUnique(mv.Introduced, ev.Introduced)
+					}
+					if ev.Fixed != "" {
+						mv.Fixed = appendUnique(mv.Fixed, ev.Fixed)
+					}
+				}
+			}
+			sort.Strings(mv.Introduced)
+			sort.Strings(mv.Fixed)
+			path := affected.Module.Path
+			modules[path] = append(modules[path], mv)
+		}
+	}
+	for _, vulns := range modules {
+		sort.Slice(vulns, func(i, j int) bool { return vulns[i].ID < vulns[j].ID })
+	}
+	return modules
+}
+
+func appendUnique(ss []string, s string) []string {
+	for _, existing := range ss {
+		if existing == s {
+			return ss
+		}
+	}
+	return append(ss, s)
+}
+
+// validate checks that every affected module in entries was recorded in
+// modules, so that a client relying solely on index/modules.json can
+// never miss a module that the full OSV data says it should see.
+func validate(entries []Entry, modules Modules) error {
+	for _, e := range entries {
+		for _, affected := range e.OSV.Affected {
+			path := affected.Module.Path
+			vulns, ok := modules[path]
+			if !ok {
+				return fmt.Errorf("%s: module %s missing from modules.json", e.ID, path)
+			}
+			found := false
+			for _, v := range vulns {
+				if v.ID == e.ID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("%s: module %s does not list this ID in modules.json", e.ID, path)
+			}
+		}
+	}
+	return nil
+}
+
+// moduleSetChanged reports whether e's per-ID file needs to be rewritten,
+// i.e., whether any module it affects has different vulns in modules vs.
+// prev.
+func moduleSetChanged(modules, prev Modules, e Entry) bool {
+	if prev == nil {
+		return true
+	}
+	for _, affected := range e.OSV.Affected {
+		path := affected.Module.Path
+		if !sameModuleVulns(modules[path], prev[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameModuleVulns(a, b []ModuleVuln) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID || !a[i].Modified.Equal(b[i].Modified) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeJSONAtomic marshals v as indented JSON and writes it to path,
+// first writing to a temporary file in the same directory and renaming
+// it into place, so that readers never observe a partially-written file.
+func writeJSONAtomic(path string, v any) (err error) {
+	derrors.Wrap(&err, "writeJSONAtomic(%s)", path)
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,293 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package refcheck checks that the URLs referenced by a report are still
+// reachable. It replaces the single-threaded, uncached checkRefs that
+// used to live in cmd/vulnreport/fix.go: checks run concurrently, results
+// are cached by ETag/Last-Modified so that unchanged references are
+// skipped on the next run, and failures are classified so that only
+// genuinely dead links are treated as errors.
+package refcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Status classifies the outcome of checking a reference URL.
+type Status int
+
+const (
+	// StatusOK means the URL resolved successfully.
+	StatusOK Status = iota
+	// StatusRedirected means the URL resolved successfully, but only
+	// after one or more redirects; Result.FinalURL holds the destination.
+	StatusRedirected
+	// StatusDNS means the host could not be resolved.
+	StatusDNS
+	// StatusTLS means the TLS handshake failed.
+	StatusTLS
+	// StatusNotFound means the server returned 404 or 410: the resource
+	// is unambiguously gone.
+	StatusNotFound
+	// StatusForbidden means the server returned 403: the link may still
+	// be viewable in a browser (e.g. behind a login or a WAF), so this
+	// is not treated as a hard failure.
+	StatusForbidden
+	// StatusServerError means the server returned a 5xx status.
+	StatusServerError
+	// StatusRateLimited means the server returned 429.
+	StatusRateLimited
+	// StatusUnknown covers any other network error.
+	StatusUnknown
+)
+
+// Dead reports whether s should be treated as a genuinely dead link,
+// rather than a transient or ambiguous failure that's only worth a note.
+func (s Status) Dead() bool {
+	switch s {
+	case StatusNotFound, StatusDNS:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusRedirected:
+		return "redirected"
+	case StatusDNS:
+		return "dns error"
+	case StatusTLS:
+		return "tls error"
+	case StatusNotFound:
+		return "not found"
+	case StatusForbidden:
+		return "forbidden"
+	case StatusServerError:
+		return "server error"
+	case StatusRateLimited:
+		return "rate limited"
+	default:
+		return "unknown error"
+	}
+}
+
+// Result is the outcome of checking a single reference URL.
+type Result struct {
+	URL      string // the URL as it appears in the report
+	FinalURL string // the URL after following redirects (== URL if none)
+	Status   Status
+	Code     int // the HTTP status code, if one was received
+	Err      error
+}
+
+// cacheEntry is what's persisted between runs for a single URL.
+type cacheEntry struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FinalURL     string    `json:"finalUrl,omitempty"`
+	Status       Status    `json:"status"`
+	Code         int       `json:"code,omitempty"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// Checker checks reference URLs with a bounded-concurrency worker pool,
+// caching results by ETag/Last-Modified across runs.
+type Checker struct {
+	// Concurrency is the number of checks to run at once. Defaults to 10.
+	Concurrency int
+	// Client is the HTTP client used for checks. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// New creates a Checker with the given concurrency.
+func New(concurrency int) *Checker {
+	return &Checker{
+		Concurrency: concurrency,
+		cache:       make(map[string]cacheEntry),
+	}
+}
+
+// LoadCache loads a previously-saved cache from path. It is not an error
+// if path does not exist.
+func (c *Checker) LoadCache(path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return json.Unmarshal(b, &c.cache)
+}
+
+// SaveCache writes the checker's cache to path.
+func (c *Checker) SaveCache(path string) error {
+	c.mu.Lock()
+	b, err := json.MarshalIndent(c.cache, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// CheckAll checks every URL in urls, using up to c.Concurrency workers,
+// and returns one Result per URL in the same order.
+func (c *Checker) CheckAll(ctx context.Context, urls []string) []Result {
+	n := c.Concurrency
+	if n <= 0 {
+		n = 10
+	}
+
+	results := make([]Result, len(urls))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = c.check(ctx, urls[i])
+			}
+		}()
+	}
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// check checks a single URL, consulting and updating the cache.
+func (c *Checker) check(ctx context.Context, url string) Result {
+	c.mu.Lock()
+	cached, ok := c.cache[url]
+	c.mu.Unlock()
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return Result{URL: url, Status: StatusUnknown, Err: err}
+	}
+	if ok {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return classifyErr(url, err)
+	}
+	resp.Body.Close()
+
+	// Some servers don't support HEAD; fall back to a small ranged GET
+	// so we don't download the whole resource just to check liveness.
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		resp, err = rangedGet(ctx, client, url)
+		if err != nil {
+			return classifyErr(url, err)
+		}
+		defer resp.Body.Close()
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		return Result{URL: url, FinalURL: cached.FinalURL, Status: cached.Status, Code: cached.Code}
+	}
+
+	finalURL := url
+	if resp.Request != nil && resp.Request.URL != nil {
+		finalURL = resp.Request.URL.String()
+	}
+
+	result := Result{URL: url, FinalURL: finalURL, Code: resp.StatusCode}
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300 && finalURL != url:
+		result.Status = StatusRedirected
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		result.Status = StatusOK
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		result.Status = StatusNotFound
+	case resp.StatusCode == http.StatusForbidden:
+		result.Status = StatusForbidden
+	case resp.StatusCode == http.StatusTooManyRequests:
+		result.Status = StatusRateLimited
+	case resp.StatusCode >= 500:
+		result.Status = StatusServerError
+	default:
+		result.Status = StatusUnknown
+	}
+
+	c.mu.Lock()
+	c.cache[url] = cacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FinalURL:     finalURL,
+		Status:       result.Status,
+		Code:         result.Code,
+		CheckedAt:    time.Now(),
+	}
+	c.mu.Unlock()
+
+	return result
+}
+
+// rangedGet performs a GET request for just the first byte of url, for
+// servers that reject HEAD requests.
+func rangedGet(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	return client.Do(req)
+}
+
+func classifyErr(url string, err error) Result {
+	r := Result{URL: url, Status: StatusUnknown, Err: err}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		r.Status = StatusDNS
+		return r
+	}
+
+	var certErr x509.UnknownAuthorityError
+	var invalidCertErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &certErr) || errors.As(err, &invalidCertErr) || errors.As(err, &recordErr) {
+		r.Status = StatusTLS
+		return r
+	}
+
+	return r
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package checks implements the network-touching checks that
+// `vulnreport fix` runs on a report: that its references are reachable,
+// that its derived symbols are up to date, and that it isn't missing any
+// cross-ecosystem aliases. Each check is expressed against a small
+// interface (RefChecker, SymbolChecker, AliasSource) rather than calling
+// http.Head, symbols.Exported, or a network alias source directly, so
+// that vulnreport can run offline or under test with an in-memory fake.
+package checks
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/vulndb/internal/osvutils"
+	"golang.org/x/vulndb/internal/proxy"
+	"golang.org/x/vulndb/internal/refcheck"
+	"golang.org/x/vulndb/internal/report"
+)
+
+// RefChecker checks that a set of reference URLs are reachable.
+// *refcheck.Checker satisfies this interface.
+type RefChecker interface {
+	CheckAll(ctx context.Context, urls []string) []refcheck.Result
+}
+
+// SymbolChecker derives the symbols exported by a package (Exported),
+// and, in binary mode, the subset of candidate symbols that are
+// unreachable in a representative binary build (Binary).
+type SymbolChecker interface {
+	Exported(m *report.Module, p *report.Package) ([]string, error)
+	Binary(pc *proxy.Client, m *report.Module, p *report.Package, candidates []string) ([]string, error)
+}
+
+// AliasSource finds vulns in other ecosystems that are related to r, and
+// fetches the raw advisory text behind r's existing aliases.
+type AliasSource interface {
+	FindRelatedVulns(ctx context.Context, r *report.Report) ([]string, error)
+
+	// FetchAdvisoryText returns the raw CVE/GHSA advisory text for one of
+	// r's existing aliases, for use as the source text when drafting
+	// TODO fields (see fixer.draftTODOs). It returns "" if none of r's
+	// aliases have advisory text available.
+	FetchAdvisoryText(ctx context.Context, r *report.Report) (string, error)
+}
+
+// Mode selects which of SymbolChecker's analyses Symbols runs.
+type Mode string
+
+const (
+	ModeSource Mode = "source"
+	ModeBinary Mode = "binary"
+	ModeBoth   Mode = "both"
+)
+
+func (m Mode) runs(which Mode) bool { return m == which || m == ModeBoth }
+
+// Logf logs a message about a check; warnings and fix errors are both
+// expressed this way so that callers can route them through their own
+// logger (cmd/vulnreport/log) without this package depending on it.
+type Logf func(format string, args ...any)
+
+// Refs checks that every reference in refs is reachable, using rc's
+// worker pool and cache. Dead links (see refcheck.Status.Dead) are
+// reported via fixErr; other non-OK statuses (rate-limiting, a 403 that
+// may still be browsable, a 5xx that may be transient) are reported via
+// warn instead, since they aren't unambiguous failures.
+//
+// If fixRefs is true, references that redirected are rewritten in place
+// to their final URL, and references whose (possibly-rewritten) URL
+// duplicates an earlier one are dropped. Refs returns the references to
+// keep in a new slice; if fixRefs is false, it holds every reference in
+// refs, in the same order.
+func Refs(ctx context.Context, rc RefChecker, refs []*report.Reference, fixRefs bool, fixErr, warn Logf) []*report.Reference {
+	if len(refs) == 0 {
+		return refs
+	}
+
+	urls := make([]string, len(refs))
+	for i, ref := range refs {
+		urls[i] = ref.URL
+	}
+	results := rc.CheckAll(ctx, urls)
+
+	seen := make(map[string]bool)
+	kept := refs[:0:0] // new backing array; refs may be reused by the caller
+	for i, ref := range refs {
+		res := results[i]
+		switch {
+		case res.Status.Dead():
+			fixErr("%q may not exist: %s", ref.URL, res.Status)
+		case res.Status != refcheck.StatusOK && res.Status != refcheck.StatusRedirected:
+			warn("%q returned a possibly-transient error: %s", ref.URL, res.Status)
+		}
+
+		if fixRefs && res.Status == refcheck.StatusRedirected && res.FinalURL != "" {
+			warn("rewriting reference %q to final URL %q", ref.URL, res.FinalURL)
+			ref.URL = res.FinalURL
+		}
+
+		if !fixRefs {
+			kept = append(kept, ref)
+			continue
+		}
+		if seen[ref.URL] {
+			warn("dropping duplicate reference %q", ref.URL)
+			continue
+		}
+		seen[ref.URL] = true
+		kept = append(kept, ref)
+	}
+
+	return kept
+}
+
+// RemoveExcluded removes any symbol in excluded from syms, calling
+// removed for each one that's dropped.
+func RemoveExcluded(syms, excluded []string, removed func(sym string)) []string {
+	if len(excluded) == 0 {
+		return syms
+	}
+	var out []string
+	for _, s := range syms {
+		if slices.Contains(excluded, s) {
+			removed(s)
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// Symbols updates the derived symbols (mode source or both) and logs any
+// symbols found to be unreachable in a representative binary build (mode
+// binary or both) for every package in r, using sc.
+//
+// warn is called for non-fatal issues, including the Go-standard-library
+// version mismatches that used to only be logged directly by
+// cmd/vulnreport/fix.go: when the running Go version isn't in a report's
+// vulnerable range, or doesn't match its vulnerable_at version.
+func Symbols(ctx context.Context, mode Mode, pc *proxy.Client, sc SymbolChecker, r *report.Report, warn Logf) error {
+	if r.IsExcluded() {
+		warn("excluded, skipping symbol checks")
+		return nil
+	}
+
+	for _, m := range r.Modules {
+		if m.IsFirstParty() {
+			gover := runtime.Version()
+			ver := semverForGoVersion(gover)
+			// If some symbol is in the std library at a different version,
+			// we may derive the wrong symbols for this package and other.
+			// In this case, skip updating DerivedSymbols.
+			affected, err := osvutils.AffectsSemver(report.AffectedRanges(m.Versions), ver)
+			if err != nil {
+				return err
+			}
+			if ver == "" || !affected {
+				warn("current Go version %q is not in a vulnerable range, skipping symbol checks for module %s", gover, m.Module)
+				continue
+			}
+			if ver != m.VulnerableAt {
+				warn("current Go version %q does not match vulnerable_at version (%s) for module %s", ver, m.VulnerableAt, m.Module)
+			}
+		}
+
+		for _, p := range m.Packages {
+			if p.SkipFix != "" {
+				warn("skipping symbol checks for package %s (reason: %q)", p.Package, p.SkipFix)
+				continue
+			}
+
+			var syms []string
+			if mode.runs(ModeSource) {
+				var err error
+				syms, err = sc.Exported(m, p)
+				if err != nil {
+					return fmt.Errorf("package %s: %w", p.Package, err)
+				}
+				syms = RemoveExcluded(syms, p.ExcludedSymbols, func(s string) {
+					warn("removed excluded symbol %s", s)
+				})
+				p.DerivedSymbols = syms
+			}
+
+			if mode.runs(ModeBinary) {
+				// Always probe the union of the human-authored Symbols
+				// and the derived set, in both binary-only and both
+				// mode -- in both mode, syms holds only the
+				// Exported-derived set, and using it alone would drop
+				// p.Symbols from the binary check.
+				candidates := append(append([]string{}, p.Symbols...), p.DerivedSymbols...)
+				unreachable, err := sc.Binary(pc, m, p, candidates)
+				if err != nil {
+					warn("binary symbol check for package %s: %s", p.Package, err)
+					continue
+				}
+				p.UnreachableSymbols = unreachable
+				if len(unreachable) > 0 {
+					warn("package %s has symbols unreachable in a representative binary build: %v", p.Package, unreachable)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// RelatedVulns looks up vulns in other ecosystems related to r via src,
+// returning the IDs found.
+func RelatedVulns(ctx context.Context, src AliasSource, r *report.Report) ([]string, error) {
+	return src.FindRelatedVulns(ctx, r)
+}
+
+// tagRegexp matches go tags. The groups are:
+// 1  the major.minor version
+// 2  the patch version, or empty if none
+// 3  the entire prerelease, if present
+// 4  the prerelease type ("beta" or "rc")
+// 5  the prerelease number
+var tagRegexp = regexp.MustCompile(`^go(\d+\.\d+)(\.\d+|)((beta|rc)(\d+))?$`)
+
+// semverForGoVersion returns the semantic version for a Go version
+// string, or "" if the version string doesn't correspond to a Go
+// release or beta.
+func semverForGoVersion(v string) string {
+	m := tagRegexp.FindStringSubmatch(v)
+	if m == nil {
+		return ""
+	}
+	version := m[1]
+	if m[2] != "" {
+		version += m[2]
+	} else {
+		version += ".0"
+	}
+	if m[3] != "" {
+		version += "-" + m[4] + "." + m[5]
+	}
+	return version
+}
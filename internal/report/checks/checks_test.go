@@ -0,0 +1,222 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/vulndb/internal/proxy"
+	"golang.org/x/vulndb/internal/refcheck"
+	"golang.org/x/vulndb/internal/report"
+)
+
+func TestRefs(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		status     refcheck.Status
+		wantDead   bool
+		wantWarned bool
+	}{
+		{"ok", refcheck.StatusOK, false, false},
+		{"redirected", refcheck.StatusRedirected, false, false},
+		{"not found", refcheck.StatusNotFound, true, false},
+		{"dns error", refcheck.StatusDNS, true, false},
+		{"rate limited (429)", refcheck.StatusRateLimited, false, true},
+		{"server error (503)", refcheck.StatusServerError, false, true},
+		{"forbidden (403)", refcheck.StatusForbidden, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			url := "https://example.com/" + test.name
+			refs := []*report.Reference{{Type: "FIX", URL: url}}
+			rc := FakeRefChecker{
+				Results: map[string]refcheck.Result{
+					url: {URL: url, FinalURL: url, Status: test.status, Code: statusCode(test.status)},
+				},
+			}
+
+			var dead, warned bool
+			fixErr := func(format string, args ...any) { dead = true }
+			warn := func(format string, args ...any) { warned = true }
+
+			kept := Refs(ctx, rc, refs, false, fixErr, warn)
+
+			if dead != test.wantDead {
+				t.Errorf("dead = %v, want %v", dead, test.wantDead)
+			}
+			if warned != test.wantWarned {
+				t.Errorf("warned = %v, want %v", warned, test.wantWarned)
+			}
+			if len(kept) != 1 {
+				t.Errorf("len(kept) = %d, want 1 (Refs should never drop references when fixRefs is false)", len(kept))
+			}
+		})
+	}
+}
+
+func TestRefsFixRefs(t *testing.T) {
+	ctx := context.Background()
+
+	orig1 := "https://example.com/old1"
+	orig2 := "https://example.com/old2"
+	final := "https://example.com/new"
+
+	refs := []*report.Reference{
+		{Type: "FIX", URL: orig1},
+		{Type: "FIX", URL: orig2},
+	}
+	rc := FakeRefChecker{
+		Results: map[string]refcheck.Result{
+			orig1: {URL: orig1, FinalURL: final, Status: refcheck.StatusRedirected},
+			orig2: {URL: orig2, FinalURL: final, Status: refcheck.StatusRedirected},
+		},
+	}
+
+	kept := Refs(ctx, rc, refs, true, func(string, ...any) {}, func(string, ...any) {})
+
+	if len(kept) != 1 {
+		t.Fatalf("len(kept) = %d, want 1 (duplicate final URL should be dropped)", len(kept))
+	}
+	if kept[0].URL != final {
+		t.Errorf("kept[0].URL = %q, want %q", kept[0].URL, final)
+	}
+}
+
+func statusCode(s refcheck.Status) int {
+	switch s {
+	case refcheck.StatusNotFound:
+		return http.StatusNotFound
+	case refcheck.StatusForbidden:
+		return http.StatusForbidden
+	case refcheck.StatusRateLimited:
+		return http.StatusTooManyRequests
+	case refcheck.StatusServerError:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusOK
+	}
+}
+
+// fakeModule and fakePackage build the minimal report.Module/Package
+// values these tests need.
+func fakeModule(firstParty bool, vulnerableAt string, packages ...*report.Package) *report.Module {
+	m := &report.Module{
+		Module:       "example.com/mod",
+		VulnerableAt: vulnerableAt,
+		Versions:     []report.VersionRange{{Introduced: "1.0.0"}},
+		Packages:     packages,
+	}
+	if firstParty {
+		m.Module = "std"
+	}
+	return m
+}
+
+func TestSymbolsStdlibVersionMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	pkg := &report.Package{Package: "net/http"}
+	m := fakeModule(true, "go1.0.0", pkg)
+	r := &report.Report{ID: "GO-2024-0001", Modules: []*report.Module{m}}
+
+	sc := FakeSymbolChecker{ExportedSymbols: map[string][]string{"net/http": {"Get"}}}
+
+	var warnings []string
+	warn := func(format string, args ...any) { warnings = append(warnings, fmt.Sprintf(format, args...)) }
+
+	if err := Symbols(ctx, ModeSource, nil, sc, r, warn); err != nil {
+		t.Fatalf("Symbols() = %v, want nil", err)
+	}
+
+	if pkg.DerivedSymbols == nil || pkg.DerivedSymbols[0] != "Get" {
+		t.Errorf("DerivedSymbols = %v, want [Get]", pkg.DerivedSymbols)
+	}
+
+	foundMismatch := false
+	for _, w := range warnings {
+		if containsAll(w, "does not match vulnerable_at version") {
+			foundMismatch = true
+		}
+	}
+	if !foundMismatch {
+		// A mismatch warning is only expected when the running Go
+		// version's derived semver differs from m.VulnerableAt, which
+		// depends on the Go version running the test. Skip instead of
+		// failing on toolchains where it happens to match.
+		if semverForGoVersion(runtime.Version()) == m.VulnerableAt {
+			t.Skip("running Go version's semver happens to match VulnerableAt; mismatch warning not exercised")
+		}
+		t.Errorf("warnings = %q, want one saying the Go version does not match vulnerable_at", warnings)
+	}
+}
+
+func TestSymbolsBinaryUnreachable(t *testing.T) {
+	ctx := context.Background()
+
+	pkg := &report.Package{Package: "example.com/mod/pkg", Symbols: []string{"Do", "Dead"}}
+	m := fakeModule(false, "", pkg)
+	r := &report.Report{ID: "GO-2024-0002", Modules: []*report.Module{m}}
+
+	sc := FakeSymbolChecker{Unreachable: map[string][]string{"example.com/mod/pkg": {"Dead"}}}
+
+	if err := Symbols(ctx, ModeBinary, nil, sc, r, func(string, ...any) {}); err != nil {
+		t.Fatalf("Symbols() = %v, want nil", err)
+	}
+
+	if want := []string{"Dead"}; len(pkg.UnreachableSymbols) != 1 || pkg.UnreachableSymbols[0] != want[0] {
+		t.Errorf("UnreachableSymbols = %v, want %v", pkg.UnreachableSymbols, want)
+	}
+}
+
+// recordingSymbolChecker is a SymbolChecker that records the candidates
+// it was asked to check in Binary, so tests can assert on them directly.
+type recordingSymbolChecker struct {
+	FakeSymbolChecker
+	gotCandidates []string
+}
+
+func (r *recordingSymbolChecker) Binary(pc *proxy.Client, m *report.Module, p *report.Package, candidates []string) ([]string, error) {
+	r.gotCandidates = candidates
+	return r.FakeSymbolChecker.Binary(pc, m, p, candidates)
+}
+
+func TestSymbolsBothModeIncludesManualSymbols(t *testing.T) {
+	ctx := context.Background()
+
+	pkg := &report.Package{Package: "example.com/mod/pkg", Symbols: []string{"Manual"}}
+	m := fakeModule(false, "", pkg)
+	r := &report.Report{ID: "GO-2024-0003", Modules: []*report.Module{m}}
+
+	sc := &recordingSymbolChecker{FakeSymbolChecker: FakeSymbolChecker{
+		ExportedSymbols: map[string][]string{"example.com/mod/pkg": {"Derived"}},
+	}}
+
+	if err := Symbols(ctx, ModeBoth, nil, sc, r, func(string, ...any) {}); err != nil {
+		t.Fatalf("Symbols() = %v, want nil", err)
+	}
+
+	if !containsAll(strings.Join(sc.gotCandidates, ","), "Manual") {
+		t.Errorf("Binary candidates = %v, want it to include the manually authored symbol %q", sc.gotCandidates, "Manual")
+	}
+}
+
+func containsAll(s, substr string) bool {
+	return len(s) >= len(substr) && (func() bool {
+		for i := 0; i+len(substr) <= len(s); i++ {
+			if s[i:i+len(substr)] == substr {
+				return true
+			}
+		}
+		return false
+	})()
+}
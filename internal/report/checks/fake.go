@@ -0,0 +1,64 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checks
+
+import (
+	"context"
+
+	"golang.org/x/vulndb/internal/proxy"
+	"golang.org/x/vulndb/internal/refcheck"
+	"golang.org/x/vulndb/internal/report"
+)
+
+// FakeRefChecker is an in-memory RefChecker, for tests and for -offline
+// mode. It reports refcheck.StatusOK for any URL not present in
+// Results.
+type FakeRefChecker struct {
+	Results map[string]refcheck.Result
+}
+
+func (f FakeRefChecker) CheckAll(ctx context.Context, urls []string) []refcheck.Result {
+	out := make([]refcheck.Result, len(urls))
+	for i, u := range urls {
+		if res, ok := f.Results[u]; ok {
+			out[i] = res
+			continue
+		}
+		out[i] = refcheck.Result{URL: u, FinalURL: u, Status: refcheck.StatusOK}
+	}
+	return out
+}
+
+// FakeSymbolChecker is an in-memory SymbolChecker, for tests and for
+// -offline mode. It returns ExportedSymbols[p.Package] without loading
+// the package, and Unreachable[p.Package] without building a binary.
+type FakeSymbolChecker struct {
+	ExportedSymbols map[string][]string
+	Unreachable     map[string][]string
+}
+
+func (f FakeSymbolChecker) Exported(m *report.Module, p *report.Package) ([]string, error) {
+	return f.ExportedSymbols[p.Package], nil
+}
+
+func (f FakeSymbolChecker) Binary(pc *proxy.Client, m *report.Module, p *report.Package, candidates []string) ([]string, error) {
+	return f.Unreachable[p.Package], nil
+}
+
+// FakeAliasSource is an in-memory AliasSource, for tests and for
+// -offline mode. It always reports Related[r.ID] and AdvisoryText[r.ID],
+// defaulting to none.
+type FakeAliasSource struct {
+	Related      map[string][]string
+	AdvisoryText map[string]string
+}
+
+func (f FakeAliasSource) FindRelatedVulns(ctx context.Context, r *report.Report) ([]string, error) {
+	return f.Related[r.ID], nil
+}
+
+func (f FakeAliasSource) FetchAdvisoryText(ctx context.Context, r *report.Report) (string, error) {
+	return f.AdvisoryText[r.ID], nil
+}
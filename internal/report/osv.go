@@ -66,6 +66,7 @@ func (r *Report) GenerateOSVEntry(goID string, lastModified time.Time) osv.Entry
 		})
 	}
 	entry.Aliases = r.GetAliases()
+	entry.Related = r.RelatedVulns
 	return entry
 }
 
@@ -0,0 +1,135 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package report reads and writes the YAML reports that describe a single
+// Go vulnerability, and derives other formats (OSV, CVE) from them.
+package report
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// ReportsDir is the name of the directory in the vulndb repo that
+// contains YAML reports.
+var ReportsDir = "data/reports"
+
+// GetReportFilename returns the path to the YAML report for goID,
+// relative to the repo root.
+func GetReportFilename(goID string) string {
+	return filepath.Join(ReportsDir, goID+".yaml")
+}
+
+// Description is the free-form, human-readable description of a vuln, in
+// the style of a Go vulnerability advisory (see GenerateOSVEntry).
+type Description string
+
+// Summary is a one-line, <=100 character summary of a vuln.
+type Summary string
+
+// NoteType classifies a Note attached to a report by AddNote.
+type NoteType string
+
+// NoteTypeFix marks a note recorded while running `vulnreport fix`.
+const NoteTypeFix NoteType = "fix"
+
+// VersionRange is a single introduced/fixed pair describing part of a
+// module's vulnerable range.
+type VersionRange struct {
+	Introduced string `yaml:"introduced,omitempty"`
+	Fixed      string `yaml:"fixed,omitempty"`
+}
+
+// Reference is a single URL cited by a report, e.g. a fix commit or an
+// advisory.
+type Reference struct {
+	Type string `yaml:"type"`
+	URL  string `yaml:"url"`
+}
+
+// CVEMeta holds the CVE metadata for reports whose CVE is assigned by the
+// Go CNA, rather than merely aliased.
+type CVEMeta struct {
+	ID  string `yaml:"id"`
+	CWE string `yaml:"cwe"`
+}
+
+// Package describes a single vulnerable package within a Module.
+type Package struct {
+	Package string `yaml:"package"`
+
+	// GOOS and GOARCH restrict the vuln to the given build constraints,
+	// if non-empty.
+	GOOS   []string `yaml:"goos,omitempty"`
+	GOARCH []string `yaml:"goarch,omitempty"`
+
+	// Symbols is the set of symbols known, from the advisory text or
+	// manual research, to be vulnerable. DerivedSymbols is the set
+	// derived automatically from Symbols by static analysis (see
+	// internal/symbols.Exported and checks.Symbols). ExcludedSymbols
+	// lists derived symbols to discard, e.g. because they're false
+	// positives from an overly broad call graph.
+	Symbols         []string `yaml:"symbols,omitempty"`
+	DerivedSymbols  []string `yaml:"derived_symbols,omitempty"`
+	ExcludedSymbols []string `yaml:"excluded_symbols,omitempty"`
+
+	// UnreachableSymbols is the subset of Symbols and DerivedSymbols
+	// that internal/symbols.Binary found to be absent from a
+	// representative binary build of the package at VulnerableAt,
+	// i.e. symbols that appear to be stripped, inlined, or eliminated
+	// as dead code in practice. It is populated by checks.Symbols in
+	// binary or both mode, and is advisory only: it does not remove
+	// anything from Symbols or DerivedSymbols, since source-level
+	// reachability can still differ by caller.
+	UnreachableSymbols []string `yaml:"unreachable_symbols,omitempty"`
+
+	// SkipFix, if non-empty, is the reason symbol checks are skipped
+	// for this package during `vulnreport fix`.
+	SkipFix string `yaml:"skip_fix,omitempty"`
+}
+
+// Module describes a single vulnerable module and the packages within it.
+type Module struct {
+	Module string `yaml:"module"`
+
+	// VulnerableAt is the representative version used to derive
+	// symbols and run the binary check.
+	VulnerableAt string `yaml:"vulnerable_at,omitempty"`
+
+	Versions []VersionRange `yaml:"versions,omitempty"`
+	Packages []*Package     `yaml:"packages,omitempty"`
+}
+
+// Report is the in-memory form of a single YAML vulnerability report.
+type Report struct {
+	ID string `yaml:"id"`
+
+	Modules []*Module `yaml:"modules,omitempty"`
+
+	Description Description  `yaml:"description,omitempty"`
+	Summary     Summary      `yaml:"summary,omitempty"`
+	Credits     []string     `yaml:"credits,omitempty"`
+	References  []*Reference `yaml:"references,omitempty"`
+
+	// CVEs and CVEMetadata list this vuln's CVE aliases: CVEMetadata is
+	// set for CVEs assigned by the Go CNA (whose metadata this repo
+	// owns), and CVEs holds the IDs of any others.
+	CVEs        []string `yaml:"cves,omitempty"`
+	CVEMetadata *CVEMeta `yaml:"cve_metadata,omitempty"`
+
+	// GHSAs lists this vuln's GitHub Security Advisory aliases.
+	GHSAs []string `yaml:"ghsas,omitempty"`
+
+	// RelatedVulns lists non-Go vuln IDs (CVE, GHSA, PYSEC, RUSTSEC,
+	// etc.) that checks.RelatedVulns found to plausibly describe the
+	// same underlying issue in another ecosystem. Unlike CVEs, GHSAs,
+	// and CVEMetadata, these are not aliases of this report -- they are
+	// surfaced to OSV consumers via GenerateOSVEntry's "related" field
+	// so that cross-ecosystem tooling can find them, not merged into
+	// GetAliases.
+	RelatedVulns []string `yaml:"related_vulns,omitempty"`
+
+	Published time.Time  `yaml:"published,omitempty"`
+	Withdrawn *time.Time `yaml:"withdrawn,omitempty"`
+}
@@ -0,0 +1,269 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package symbols
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/vulndb/internal/osvutils"
+	"golang.org/x/vulndb/internal/proxy"
+	"golang.org/x/vulndb/internal/report"
+)
+
+// Binary downloads the VulnerableAt version of m via pc, builds a small
+// binary that imports p, and inspects the resulting binary's symbol
+// table to determine which of candidates (typically p.Symbols plus
+// p.DerivedSymbols, as computed by Exported) are actually reachable from
+// the binary's exported API surface -- mirroring the "binary" analysis
+// mode that govulncheck added alongside its source-based analysis.
+//
+// It returns the subset of candidates that were NOT found in the
+// binary's symbol table, i.e. those that appear to be stripped, inlined,
+// or eliminated as dead code in a representative build. Candidates that
+// probe skipped (see probe) are not included. Callers store this in
+// report.Package.UnreachableSymbols.
+func Binary(pc *proxy.Client, m *report.Module, p *report.Package, candidates []string) (unreachable []string, err error) {
+	ver := m.VulnerableAt
+	if ver == "" {
+		return nil, fmt.Errorf("binary: module %s has no vulnerable_at version set", m.Module)
+	}
+	if affected, err := osvutils.AffectsSemver(report.AffectedRanges(m.Versions), ver); err != nil {
+		return nil, err
+	} else if !affected {
+		return nil, fmt.Errorf("binary: vulnerable_at version %s for module %s is not in the vulnerable range", ver, m.Module)
+	}
+
+	dir, err := os.MkdirTemp("", "vulnreport-binary-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	modDir, err := fetchModule(pc, m.Module, ver, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	found, skipped, err := probe(modDir, m.Module, p.Package, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range candidates {
+		if !skipped[c] && !found[c] {
+			unreachable = append(unreachable, c)
+		}
+	}
+	sort.Strings(unreachable)
+	return unreachable, nil
+}
+
+// fetchModule downloads modulePath at version into dir using pc, and
+// returns the directory containing its extracted source.
+func fetchModule(pc *proxy.Client, modulePath, version, dir string) (string, error) {
+	zr, err := pc.GetZip(modulePath, version)
+	if err != nil {
+		return "", fmt.Errorf("binary: fetching %s@%s: %w", modulePath, version, err)
+	}
+
+	// Per the module proxy zip protocol, every file in the zip is
+	// prefixed with "<module>@<version>/" -- the module's own go.mod
+	// (needed to resolve pkgPath when we build the importer) lives one
+	// level below dir, not at dir itself. Read the prefix off the zip
+	// rather than reconstructing it from modulePath, since the proxy
+	// escapes upper-case letters in the path.
+	prefix, err := zipPrefix(zr)
+	if err != nil {
+		return "", fmt.Errorf("binary: %s@%s: %w", modulePath, version, err)
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			rc.Close()
+			return "", err
+		}
+		out, err := os.Create(dest)
+		if err != nil {
+			rc.Close()
+			return "", err
+		}
+		_, copyErr := out.ReadFrom(rc)
+		rc.Close()
+		out.Close()
+		if copyErr != nil {
+			return "", copyErr
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(prefix)), nil
+}
+
+// zipPrefix returns the "<module>@<version>/" directory prefix shared by
+// every file in zr, per the module proxy zip protocol
+// (https://go.dev/ref/mod#zip-files).
+func zipPrefix(zr *zip.Reader) (string, error) {
+	for _, f := range zr.File {
+		name := filepath.ToSlash(f.Name)
+		// The module path itself may contain slashes (e.g.
+		// "example.com/foo@v1.2.3/..."), so the prefix ends at the
+		// first "/" after the "@version" component, not the first "/"
+		// in the path.
+		at := strings.Index(name, "@")
+		if at < 0 {
+			continue
+		}
+		if slash := strings.Index(name[at:], "/"); slash >= 0 {
+			return name[:at+slash+1], nil
+		}
+	}
+	return "", fmt.Errorf("empty module zip")
+}
+
+// probe builds one or more importer binaries referencing candidates and
+// returns which of them were found reachable in a binary build, as
+// determined by symbolsInBinary.
+//
+// It first tries a single binary referencing every candidate, since
+// that's one `go build` instead of len(candidates). If that build
+// fails -- e.g. because one candidate is a bare type name rather than a
+// value expression, which var _ = pkg.X rejects -- a single bad
+// reference would otherwise sink the check for every symbol in the
+// package, so probe falls back to building one binary per candidate and
+// skips whichever candidates fail on their own. Skipped candidates are
+// excluded from the caller's unreachable computation entirely, since
+// probe was unable to determine their reachability either way.
+func probe(modDir, modulePath, pkgPath string, candidates []string) (found, skipped map[string]bool, err error) {
+	mainDir := filepath.Join(modDir, "vulnreport-binary-main")
+	binPath, buildErr := buildImporter(mainDir, modulePath, pkgPath, candidates)
+	if buildErr == nil {
+		found, err = symbolsInBinary(binPath, pkgPath)
+		return found, nil, err
+	}
+
+	found = make(map[string]bool)
+	skipped = make(map[string]bool)
+	for i, c := range candidates {
+		oneDir := filepath.Join(mainDir, fmt.Sprintf("candidate%d", i))
+		binPath, err := buildImporter(oneDir, modulePath, pkgPath, []string{c})
+		if err != nil {
+			skipped[c] = true
+			continue
+		}
+		f, err := symbolsInBinary(binPath, pkgPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		for s := range f {
+			found[s] = true
+		}
+	}
+	return found, skipped, nil
+}
+
+// buildImporter writes a one-file main package that imports pkgPath and
+// references each of candidates directly, then builds it, returning the
+// path to the resulting binary.
+//
+// A blank import on its own isn't enough: it makes the compiler check
+// pkgPath, but references none of its exported API, so the linker's dead
+// code elimination strips virtually everything before symbolsInBinary
+// ever runs `go tool nm` on the result. Referencing each candidate by
+// name keeps it (and whatever it calls) reachable, so "not found in the
+// binary" actually means "eliminated as dead code", not "never
+// referenced by this probe".
+func buildImporter(mainDir, modulePath, pkgPath string, candidates []string) (string, error) {
+	var body strings.Builder
+	for _, c := range candidates {
+		if typ, method, ok := strings.Cut(c, "."); ok {
+			// A method expression like (*pkg.Type).Method is valid
+			// regardless of whether Method has a value or pointer
+			// receiver, and doesn't require constructing a pkg.Type.
+			fmt.Fprintf(&body, "\tvar _ = (*pkg.%s).%s\n", typ, method)
+		} else {
+			fmt.Fprintf(&body, "\tvar _ = pkg.%s\n", c)
+		}
+	}
+
+	var src string
+	if body.Len() == 0 {
+		src = fmt.Sprintf("package main\n\nimport _ %q\n\nfunc main() {}\n", pkgPath)
+	} else {
+		src = fmt.Sprintf("package main\n\nimport pkg %q\n\nfunc main() {\n%s}\n", pkgPath, body.String())
+	}
+	if err := os.MkdirAll(mainDir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(mainDir, "main.go"), []byte(src), 0644); err != nil {
+		return "", err
+	}
+
+	binPath := filepath.Join(mainDir, "importer")
+	cmd := exec.Command("go", "build", "-o", binPath, ".")
+	cmd.Dir = mainDir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("binary: building importer for %s: %w\n%s", modulePath, err, out)
+	}
+	return binPath, nil
+}
+
+// symbolsInBinary returns the set of symbols from pkgPath, exported or
+// not, that appear in the binary at binPath, as reported by `go tool nm`.
+func symbolsInBinary(binPath, pkgPath string) (map[string]bool, error) {
+	cmd := exec.Command("go", "tool", "nm", binPath)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("binary: nm %s: %w", binPath, err)
+	}
+
+	prefix := pkgPath + "."
+	found := make(map[string]bool)
+	sc := bufio.NewScanner(strings.NewReader(string(out)))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		name := fields[2]
+		if after, ok := strings.CutPrefix(name, prefix); ok {
+			found[normalizeSymbol(after)] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// methodSymbolRegexp matches the nm-style rendering of a method symbol
+// with its receiver type parenthesized, e.g. "(*Type).Method" or
+// "(Type).Method".
+var methodSymbolRegexp = regexp.MustCompile(`^\(\*?([^)]+)\)\.(.+)$`)
+
+// normalizeSymbol converts an nm-style symbol name, with the package
+// prefix already stripped, into the "Type.Method" form used by Exported
+// and by a report's symbols list, so the two can be compared directly.
+// Plain function and variable names are returned unchanged.
+func normalizeSymbol(s string) string {
+	if m := methodSymbolRegexp.FindStringSubmatch(s); m != nil {
+		return m[1] + "." + m[2]
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package symbols
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestZipPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("example.com/foo@v1.2.3/go.mod"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zw.Create("example.com/foo@v1.2.3/foo.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := zipPrefix(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "example.com/foo@v1.2.3/"; got != want {
+		t.Errorf("zipPrefix() = %q, want %q", got, want)
+	}
+}
+
+func TestZipPrefixEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := zipPrefix(zr); err == nil {
+		t.Error("zipPrefix() on an empty zip: got nil error, want non-nil")
+	}
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Func", "Func"},
+		{"(*Type).Method", "Type.Method"},
+		{"(Type).Method", "Type.Method"},
+	}
+	for _, test := range tests {
+		if got := normalizeSymbol(test.in); got != test.want {
+			t.Errorf("normalizeSymbol(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}